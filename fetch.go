@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cjlint/go-webcrawler/politeness"
+	"golang.org/x/net/html"
+)
+
+// fetchResult is the successful outcome of fetching and parsing a page.
+type fetchResult struct {
+	req     *http.Request
+	resp    *http.Response
+	doc     *html.Node
+	rawBody []byte
+}
+
+const (
+	maxFetchRetries = 3
+	retryBaseDelay  = 500 * time.Millisecond
+	retryMaxDelay   = 8 * time.Second
+)
+
+// retryBackoff returns how long to wait before retry attempt (0-based),
+// doubling each time up to retryMaxDelay.
+func retryBackoff(attempt int) time.Duration {
+	delay := retryBaseDelay * time.Duration(1<<attempt)
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// fetchWithRetry fetches info.val, retrying retryable FetchErrors with
+// exponential backoff up to maxFetchRetries times. teeBody controls
+// whether the raw response bytes are captured alongside the parsed
+// document, for WARC archiving.
+func fetchWithRetry(ctx context.Context, client *http.Client, pol *politeness.Policy, info urlInfo, maxBodyBytes int64, teeBody bool) (*fetchResult, error) {
+	var lastErr error
+	for attempt := 0; attempt <= maxFetchRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(retryBackoff(attempt - 1)):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		result, err := fetchOnce(ctx, client, pol, info, maxBodyBytes, teeBody)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		var fe *FetchError
+		if !errors.As(err, &fe) || !fe.retryable() {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+func fetchOnce(ctx context.Context, client *http.Client, pol *politeness.Policy, info urlInfo, maxBodyBytes int64, teeBody bool) (*fetchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, info.val, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pol.Wait(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	defer pol.Release()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, classifyFetchError(info.val, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &FetchError{Kind: KindHTTPStatus, URL: info.val, StatusCode: resp.StatusCode}
+	}
+
+	// Cap how much of the body we actually read: a server can lie about
+	// (or omit) Content-Length, and the HEAD precheck in main.go only
+	// catches that case when the server answers HEAD honestly.
+	var rawBody bytes.Buffer
+	var bodyReader io.Reader = io.LimitReader(resp.Body, maxBodyBytes)
+	if teeBody {
+		bodyReader = io.TeeReader(bodyReader, &rawBody)
+	}
+	doc, err := html.Parse(bodyReader)
+	if err != nil {
+		return nil, &FetchError{Kind: KindParseHTML, URL: info.val, Err: err}
+	}
+
+	return &fetchResult{req: req, resp: resp, doc: doc, rawBody: rawBody.Bytes()}, nil
+}
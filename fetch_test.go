@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cjlint/go-webcrawler/politeness"
+)
+
+func TestFetchWithRetryRetriesOnServerError(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			return
+		}
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	defer srv.Close()
+
+	client := srv.Client()
+	pol := politeness.New("go-webcrawler/1.0", client, 1000, 1000, 50)
+
+	result, err := fetchWithRetry(context.Background(), client, pol, urlInfo{val: srv.URL, depth: 1}, 1<<20, false)
+	if err != nil {
+		t.Fatalf("expected eventual success, got %v", err)
+	}
+	if result == nil {
+		t.Fatal("expected a non-nil result")
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests (2 failures then a success), got %d", requests)
+	}
+}
+
+func TestFetchWithRetryStopsOnNonRetryableError(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			return
+		}
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	client := srv.Client()
+	pol := politeness.New("go-webcrawler/1.0", client, 1000, 1000, 50)
+
+	_, err := fetchWithRetry(context.Background(), client, pol, urlInfo{val: srv.URL, depth: 1}, 1<<20, false)
+	if err == nil {
+		t.Fatal("expected an error for a 404 response")
+	}
+	if requests != 1 {
+		t.Errorf("expected a 404 to stop after 1 request, got %d", requests)
+	}
+}
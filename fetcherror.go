@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// FetchErrorKind classifies why a page fetch failed, so callers can
+// decide whether it's worth retrying and how to report it.
+type FetchErrorKind int
+
+const (
+	KindDNS FetchErrorKind = iota
+	KindTimeout
+	KindHTTPStatus
+	KindParseHTML
+	KindRobotsDenied
+)
+
+func (k FetchErrorKind) String() string {
+	switch k {
+	case KindDNS:
+		return "dns"
+	case KindTimeout:
+		return "timeout"
+	case KindHTTPStatus:
+		return "http-status"
+	case KindParseHTML:
+		return "parse-html"
+	case KindRobotsDenied:
+		return "robots-denied"
+	default:
+		return "unknown"
+	}
+}
+
+// FetchError is a typed error describing a failed attempt to fetch a URL.
+// StatusCode is only meaningful when Kind is KindHTTPStatus.
+type FetchError struct {
+	Kind       FetchErrorKind
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+func (e *FetchError) Error() string {
+	switch e.Kind {
+	case KindHTTPStatus:
+		return fmt.Sprintf("%s: %s: HTTP %d", e.URL, e.Kind, e.StatusCode)
+	case KindRobotsDenied:
+		return fmt.Sprintf("%s: %s", e.URL, e.Kind)
+	default:
+		return fmt.Sprintf("%s: %s: %v", e.URL, e.Kind, e.Err)
+	}
+}
+
+// Unwrap exposes the underlying error, if any, so callers can use
+// errors.Is/As against it.
+func (e *FetchError) Unwrap() error { return e.Err }
+
+// retryable reports whether attempting the fetch again might succeed.
+// Permanent outcomes -- a robots.txt denial, or HTML that won't parse any
+// differently next time -- aren't retried, nor are ordinary 4xx client
+// errors; DNS hiccups, timeouts, 429s, and 5xx server errors are.
+func (e *FetchError) retryable() bool {
+	switch e.Kind {
+	case KindRobotsDenied, KindParseHTML:
+		return false
+	case KindHTTPStatus:
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+	default:
+		return true
+	}
+}
+
+// classifyFetchError turns an error returned by http.Client.Do into a
+// FetchError. DNS resolution failures are classified as KindDNS; anything
+// else reported by the transport (a timeout, a cancelled context,
+// connection refused, reset, etc.) is classified as KindTimeout, since
+// it's equally transient and retried the same way.
+func classifyFetchError(rawURL string, err error) *FetchError {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return &FetchError{Kind: KindDNS, URL: rawURL, Err: err}
+	}
+	return &FetchError{Kind: KindTimeout, URL: rawURL, Err: err}
+}
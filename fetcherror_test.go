@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestFetchErrorRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *FetchError
+		want bool
+	}{
+		{"dns", &FetchError{Kind: KindDNS}, true},
+		{"timeout", &FetchError{Kind: KindTimeout}, true},
+		{"robots-denied", &FetchError{Kind: KindRobotsDenied}, false},
+		{"parse-html", &FetchError{Kind: KindParseHTML}, false},
+		{"http-429", &FetchError{Kind: KindHTTPStatus, StatusCode: http.StatusTooManyRequests}, true},
+		{"http-500", &FetchError{Kind: KindHTTPStatus, StatusCode: http.StatusInternalServerError}, true},
+		{"http-404", &FetchError{Kind: KindHTTPStatus, StatusCode: http.StatusNotFound}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.err.retryable(); got != tt.want {
+			t.Errorf("%s: retryable() = %v, expected %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFetchErrorError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *FetchError
+		want string
+	}{
+		{"http-status", &FetchError{Kind: KindHTTPStatus, URL: "http://example.com", StatusCode: 503}, "http://example.com: http-status: HTTP 503"},
+		{"robots-denied", &FetchError{Kind: KindRobotsDenied, URL: "http://example.com/x"}, "http://example.com/x: robots-denied"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("%s: Error() = %q, expected %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyFetchErrorDNS(t *testing.T) {
+	dnsErr := &net.DNSError{Err: "no such host", Name: "nosuchhost.invalid"}
+	fe := classifyFetchError("http://nosuchhost.invalid", dnsErr)
+	if fe.Kind != KindDNS {
+		t.Errorf("expected KindDNS, got %v", fe.Kind)
+	}
+	if !errors.Is(fe.Unwrap(), dnsErr) {
+		t.Errorf("expected Unwrap to expose the underlying DNS error")
+	}
+}
+
+func TestClassifyFetchErrorOther(t *testing.T) {
+	fe := classifyFetchError("http://example.com", errors.New("connection reset"))
+	if fe.Kind != KindTimeout {
+		t.Errorf("expected KindTimeout, got %v", fe.Kind)
+	}
+}
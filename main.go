@@ -1,23 +1,38 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"net/url"
 
+	"github.com/cjlint/go-webcrawler/politeness"
+	"github.com/cjlint/go-webcrawler/scope"
 	"golang.org/x/net/html"
 )
 
+// taggedURL is a link discovered on a crawled page, together with how it
+// was discovered (see scope.LinkTag).
+type taggedURL struct {
+	val string
+	tag scope.LinkTag
+}
+
 type urlResults struct {
 	baseURL   string
-	childURLs []string
+	childURLs []taggedURL
 	depth     int
 }
 
@@ -33,37 +48,127 @@ func standardizeURL(urlObj *url.URL) string {
 	return fmt.Sprintf("https://%s%s", urlObj.Hostname(), trimmedPath)
 }
 
-func parseURLs(doc *html.Node) []string {
-	// Many URLs will be the same after sanitizing, keep a local map
-	// of seen URLs to reduce duplicates in logs
+// cssURLPattern extracts the target of a CSS url(...) reference, with or
+// without surrounding quotes.
+var cssURLPattern = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+
+// resolveLink parses raw (a href/src value found on the page), resolves it
+// against base if it's relative, and returns the resolved URL along with a
+// canonical string form for deduplication. A nil URL with a nil error means
+// raw resolved to a non-http(s) scheme (mailto:, javascript:, etc.) and
+// should be silently skipped.
+func resolveLink(base *url.URL, raw string) (*url.URL, string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, "", err
+	}
+	resolved := base.ResolveReference(parsed)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return nil, "", nil
+	}
+	// Ignore query parameters and standardize path + trailing slash, same
+	// as standardizeURL, but keep the real scheme instead of forcing https
+	// so the crawler can follow plain-http links too.
+	resolved.Path = strings.TrimRight(resolved.Path, "/")
+	resolved.RawQuery = ""
+	resolved.Fragment = ""
+	canonical := fmt.Sprintf("%s://%s%s", resolved.Scheme, resolved.Hostname(), resolved.Path)
+	return resolved, canonical, nil
+}
+
+// parseURLs walks doc (fetched from base) and returns every link it finds,
+// tagged as primary (<a href>, the page's own navigation) or related
+// (<link href>, <img src>, <script src>, and CSS url(...) references --
+// the assets the page needs to render).
+//
+// When rewriteLink is non-nil, parseURLs also mutates doc in place: every
+// http(s) href/src and CSS url(...) it finds is replaced with whatever
+// rewriteLink returns for that link's resolved URL and tag (used by mirror
+// mode to point at locally-mirrored copies; see mirror.go). Empty,
+// unparsable, and non-http(s) links (mailto:, javascript:, ...) are left
+// as they are. rewriteLink is called once per occurrence, even for links
+// already seen.
+func parseURLs(base *url.URL, doc *html.Node, rewriteLink func(resolved *url.URL, tag scope.LinkTag) string) []taggedURL {
+	// Many URLs will be the same after resolving, keep a local map of seen
+	// URLs to reduce duplicates in logs
 	seenURLs := map[string]bool{}
-	var urls []string
+	var urls []taggedURL
+
+	// resolve records raw (deduped) under tag and returns the string it
+	// should be written back as: rewriteLink's replacement, or raw
+	// unchanged when rewriteLink is nil.
+	resolve := func(raw string, tag scope.LinkTag) string {
+		if strings.TrimSpace(raw) == "" {
+			return raw
+		}
+		resolved, canonical, err := resolveLink(base, raw)
+		if err != nil {
+			log.Println("Error parsing url", raw, err)
+			return raw
+		}
+		if resolved == nil {
+			// Non-http(s) scheme (mailto:, javascript:, ...), not a crawlable link.
+			return raw
+		}
+		if !seenURLs[canonical] {
+			urls = append(urls, taggedURL{canonical, tag})
+		}
+		seenURLs[canonical] = true
+
+		if rewriteLink == nil {
+			return raw
+		}
+		return rewriteLink(resolved, tag)
+	}
+
+	attr := func(n *html.Node, key string) (string, bool) {
+		for _, a := range n.Attr {
+			if a.Key == key {
+				return a.Val, true
+			}
+		}
+		return "", false
+	}
+	setAttr := func(n *html.Node, key, val string) {
+		for i, a := range n.Attr {
+			if a.Key == key {
+				n.Attr[i].Val = val
+				return
+			}
+		}
+	}
+	rewriteCSS := func(css string, tag scope.LinkTag) string {
+		return cssURLPattern.ReplaceAllStringFunc(css, func(m string) string {
+			target := cssURLPattern.FindStringSubmatch(m)[1]
+			return strings.Replace(m, target, resolve(target, tag), 1)
+		})
+	}
 
 	// HTML parsing code adapted from
 	// https://pkg.go.dev/golang.org/x/net/html#example-Parse
 	var f func(*html.Node)
 	f = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" {
-			// found anchor element
-			for _, a := range n.Attr {
-				if a.Key == "href" {
-					// Parse url so we can standardize it
-					urlObj, err := url.Parse(strings.TrimSpace(a.Val))
-					if err != nil {
-						log.Println("Error parsing url", a.Val, err)
-					} else if urlObj.Scheme == "https" {
-						// Skip anything that isn't an absolute https url
-						// Standardize URL to prevent crawling the same URL multiple times
-						// for example, ignore query parameters and standardize path +
-						// trailing slash
-						standardizedURL := standardizeURL(urlObj)
-						if !seenURLs[standardizedURL] {
-							urls = append(urls, standardizedURL)
-						}
-						seenURLs[standardizedURL] = true
-					}
-					break
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "a":
+				if href, ok := attr(n, "href"); ok {
+					setAttr(n, "href", resolve(href, scope.TagPrimary))
+				}
+			case "link":
+				if href, ok := attr(n, "href"); ok {
+					setAttr(n, "href", resolve(href, scope.TagRelated))
 				}
+			case "img", "script":
+				if src, ok := attr(n, "src"); ok {
+					setAttr(n, "src", resolve(src, scope.TagRelated))
+				}
+			case "style":
+				if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+					n.FirstChild.Data = rewriteCSS(n.FirstChild.Data, scope.TagRelated)
+				}
+			}
+			if style, ok := attr(n, "style"); ok {
+				setAttr(n, "style", rewriteCSS(style, scope.TagRelated))
 			}
 		}
 		// Read html recursively. Iteratively would be better in case of
@@ -78,50 +183,218 @@ func parseURLs(doc *html.Node) []string {
 	return urls
 }
 
-func crawlWorker(client *http.Client, urlsToCrawl chan urlInfo, results chan urlResults) {
-	for url := range urlsToCrawl {
-		resp, err := client.Get(url.val)
-		if err != nil {
-			log.Println("Error while fetching URL", url, err)
-			return
-		}
-		doc, err := html.Parse(resp.Body)
-		if err != nil {
-			log.Println("Failed to parse body from URL", url, err)
-			return
-		}
+func crawlWorker(ctx context.Context, client *http.Client, urlsToCrawl chan urlInfo, results chan urlResults, warc *warcWriter, store crawlStateStore, pol *politeness.Policy, maxBodyBytes int64, sc scope.Scope, mirrorDir string, wg *sync.WaitGroup) {
+	// When archiving (-output) or mirroring (-mirror) is active, every URL
+	// in the frontier needs its actual bytes captured, not just HTML
+	// pages -- related links (img/script/link/CSS url()) are real,
+	// non-HTML assets, and skipping them here would silently gut WARC
+	// archives and leave mirrored pages with dead asset links.
+	archiving := warc != nil || mirrorDir != ""
+	for info := range urlsToCrawl {
+		// Wrapped in a func so `defer pol.Release()` runs at the end of
+		// each URL rather than only once the whole worker returns.
+		//
+		// wg.Done() is NOT deferred here: an item that makes it to
+		// results is only "done" once processResults has finished adding
+		// its children to the WaitGroup, so that the count never touches
+		// zero while derived work is still being queued. Every early
+		// return below short-circuits before results, so it must call
+		// wg.Done() itself.
+		func() {
+			if err := store.RemoveFrontier(info.val); err != nil {
+				log.Println("Error removing URL from persisted frontier", info.val, err)
+			}
+
+			reqURL, err := url.Parse(info.val)
+			if err != nil {
+				log.Println("Error parsing URL", info.val, err)
+				wg.Done()
+				return
+			}
+
+			if !pol.Allowed(ctx, reqURL) {
+				log.Println(&FetchError{Kind: KindRobotsDenied, URL: info.val})
+				wg.Done()
+				return
+			}
+
+			if !archiving && !precheckHTML(ctx, client, pol, reqURL, maxBodyBytes) {
+				log.Println("Skipping URL that isn't HTML or exceeds the max body size", info.val)
+				wg.Done()
+				return
+			}
+
+			result, err := fetchWithRetry(ctx, client, pol, info, maxBodyBytes, warc != nil)
+			if err != nil {
+				log.Println("Error fetching URL", info.val, err)
+				wg.Done()
+				return
+			}
 
-		childURLs := parseURLs(doc)
+			if warc != nil {
+				if err := warc.writeExchange(info.val, result.req, result.resp, result.rawBody); err != nil {
+					log.Println("Error writing WARC record for URL", info.val, err)
+				}
+			}
 
-		results <- urlResults{url.val, childURLs, url.depth}
+			var rewriteLink func(resolved *url.URL, tag scope.LinkTag) string
+			if mirrorDir != "" {
+				// parseURLs calls rewriteLink once per occurrence of a link
+				// on the page, not once per distinct link, but sc.Check
+				// (e.g. MaxLinksPerHostScope) mutates shared counters as a
+				// side effect of returning true. Memoize the decision per
+				// resolved URL so a link repeated on one page (nav + footer,
+				// say) only charges its scope budget once.
+				inScope := map[string]bool{}
+				rewriteLink = func(resolved *url.URL, tag scope.LinkTag) string {
+					childDepth := info.depth
+					if tag == scope.TagPrimary {
+						childDepth++
+					}
+					key := resolved.String()
+					allowed, checked := inScope[key]
+					if !checked {
+						allowed = sc.Check(resolved, childDepth, tag)
+						inScope[key] = allowed
+					}
+					if allowed {
+						if rel, err := relativeMirrorPath(result.resp.Request.URL, resolved); err == nil {
+							return rel
+						}
+					}
+					// Out of scope, or we couldn't compute a relative path --
+					// either way, link to the live page rather than a local
+					// copy that will never exist.
+					return resolved.String()
+				}
+			}
+			childURLs := parseURLs(result.resp.Request.URL, result.doc, rewriteLink)
+
+			if mirrorDir != "" {
+				if err := writeMirrorPage(mirrorDir, result.resp.Request.URL, result.doc); err != nil {
+					log.Println("Error writing mirrored page for URL", info.val, err)
+				}
+			}
+
+			select {
+			case results <- urlResults{info.val, childURLs, info.depth}:
+				// processResults calls wg.Done() for this item once it's
+				// done adding any children to the WaitGroup.
+			case <-ctx.Done():
+				wg.Done()
+			}
+		}()
 	}
 }
 
-func processResults(wg *sync.WaitGroup, urlsToCrawl chan urlInfo, results chan urlResults, maxDepth int) {
+// precheckHTML issues a HEAD request for u and reports whether the crawl
+// should go on to GET it: the response must look like HTML and must not
+// advertise a body bigger than maxBodyBytes. A HEAD that errors, that the
+// server refuses to answer meaningfully, or that simply doesn't say, never
+// blocks the crawl -- we fall through to the GET and let the
+// io.LimitReader there cap what's actually read.
+//
+// Only meant to be consulted when nothing needs the non-HTML assets a page
+// links to -- see the "archiving" check in crawlWorker.
+func precheckHTML(ctx context.Context, client *http.Client, pol *politeness.Policy, u *url.URL, maxBodyBytes int64) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return true
+	}
+	if err := pol.Wait(ctx, u); err != nil {
+		return true
+	}
+	defer pol.Release()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return true
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "text/html") {
+		return false
+	}
+	return resp.ContentLength <= 0 || resp.ContentLength <= maxBodyBytes
+}
+
+func processResults(ctx context.Context, wg *sync.WaitGroup, urlsToCrawl chan urlInfo, results chan urlResults, store crawlStateStore, sc scope.Scope) {
 	// Background function that prints logs in synchronous order,
 	// then sends child urls to next channel to be processed
 	//
-	// The waitgroup watches urlsToCrawl -- once it is empty the program can end.
-	crawled := map[string]bool{}
+	// The waitgroup watches urlsToCrawl -- every URL is counted done exactly
+	// once, regardless of outcome: by its worker when the worker gives up
+	// on it early, or here once its results have been processed (see
+	// crawlWorker), so the program can end even if some fetches fail. The
+	// crawled set now lives in `store` rather than an in-memory map, so
+	// it can be backed by on-disk state and survive a restart.
+	//
+	// A received item's wg.Done() (below) always fires after every
+	// wg.Add() for its children: both happen in this single goroutine, so
+	// ordering them this way guarantees the count can never touch zero --
+	// and let wg.Wait() return -- while a child it just discovered is
+	// still on its way into the queue.
 	for info := range results {
-		crawled[info.baseURL] = true
+		if err := store.MarkCrawled(info.baseURL); err != nil {
+			log.Println("Error persisting crawl state for URL", info.baseURL, err)
+		}
 		log.Printf("%s (depth %d)\n", info.baseURL, info.depth)
-		for _, childURL := range info.childURLs {
-			log.Printf("    %s\n", childURL)
+		for _, child := range info.childURLs {
+			log.Printf("    %s (%s)\n", child.val, child.tag)
 		}
-		for _, childURL := range info.childURLs {
-			if !crawled[childURL] && (info.depth < maxDepth || maxDepth == 0) {
+		if ctx.Err() == nil {
+			for _, child := range info.childURLs {
+				// Related links (page assets) are a single hop out, not a
+				// further step in the crawl, so they don't consume a depth level.
+				childDepth := info.depth
+				if child.tag == scope.TagPrimary {
+					childDepth = info.depth + 1
+				}
+
+				crawled, err := store.IsCrawled(child.val)
+				if err != nil {
+					log.Println("Error checking crawl state for URL", child.val, err)
+					continue
+				}
+				childURLObj, err := url.Parse(child.val)
+				if err != nil {
+					log.Println("Error parsing url", child.val, err)
+					continue
+				}
+				if crawled || !sc.Check(childURLObj, childDepth, child.tag) {
+					continue
+				}
+				isNew, err := store.MarkSeen(child.val)
+				if err != nil {
+					log.Println("Error marking URL seen", child.val, err)
+					continue
+				}
+				if !isNew {
+					// Already enqueued (or mid-fetch) via another parent's
+					// link to the same URL; IsCrawled above only catches
+					// URLs that have *finished*, not ones still in flight.
+					continue
+				}
+				wg.Add(1)
+				// Persist before handing off, so a worker's RemoveFrontier
+				// can't run ahead of this and leave a stale entry behind.
+				store.PutFrontier(urlInfo{child.val, childDepth})
 				// select statement ensures that this operation never blocks,
 				// even if it means we have to start throwing away URLs
 				// that don't fit in the buffer
 				select {
-				case urlsToCrawl <- urlInfo{childURL, info.depth + 1}:
-					wg.Add(1)
+				case urlsToCrawl <- urlInfo{child.val, childDepth}:
 				default:
-					log.Println("URL buffer is full, discarding URL", childURL)
+					store.RemoveFrontier(child.val)
+					wg.Done()
+					log.Println("URL buffer is full, discarding URL", child.val)
 				}
 			}
 		}
+		// Shutting down leaves any already-logged children out of the
+		// frontier and lets the pipeline drain instead of growing further.
 		wg.Done()
 	}
 	close(urlsToCrawl)
@@ -134,7 +407,69 @@ func recommendedWorkers(maxDepth int) int {
 	return int(math.Min(math.Pow10(maxDepth-1), 1000))
 }
 
-func crawl(baseURL string, maxDepth, maxWorkers int) {
+// buildScope assembles the Scope used to decide which discovered links the
+// crawl follows. When domainsCSV is empty the crawl stays on seedHost;
+// otherwise it's allowed to range over any of the listed domains (and
+// their subdomains). An optional regexpStr further restricts or widens
+// that set, combined via mode ("and" or "or"). Depth is always bounded by
+// maxDepth, regardless of mode. maxLinksPerHost and maxHostsPerDomain are
+// ignored when zero. domainLog, if non-nil, receives each registered
+// domain the crawl actually follows, the first time it's seen.
+func buildScope(seedHost string, maxDepth int, domainsCSV, regexpStr, mode string, maxLinksPerHost, maxHostsPerDomain int, domainLog io.Writer) (scope.Scope, error) {
+	var domainScopes []scope.Scope
+	if domainsCSV == "" {
+		domainScopes = append(domainScopes, scope.SeedHostScope{Host: seedHost})
+	} else {
+		for _, domain := range strings.Split(domainsCSV, ",") {
+			if domain = strings.TrimSpace(domain); domain != "" {
+				domainScopes = append(domainScopes, scope.DomainScope{Domain: domain})
+			}
+		}
+	}
+	combined := scope.Or(domainScopes...)
+
+	if regexpStr != "" {
+		pattern, err := regexp.Compile(regexpStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -scope-regexp: %w", err)
+		}
+		re := scope.RegexpScope{Pattern: pattern}
+		if mode == "or" {
+			combined = scope.Or(combined, re)
+		} else {
+			combined = scope.And(combined, re)
+		}
+	}
+
+	combined = scope.And(combined, scope.DepthScope{Max: maxDepth})
+	if maxLinksPerHost > 0 {
+		combined = scope.And(combined, &scope.MaxLinksPerHostScope{Max: maxLinksPerHost})
+	}
+	if maxHostsPerDomain > 0 {
+		combined = scope.And(combined, &scope.MaxHostsPerDomainScope{Max: maxHostsPerDomain})
+	}
+	// The domain logger only records a domain once a link has cleared every
+	// other check, so put it last.
+	if domainLog != nil {
+		combined = scope.And(combined, &scope.DomainLogger{Writer: domainLog})
+	}
+
+	return combined, nil
+}
+
+func crawl(baseURL string, maxDepth, maxWorkers int, outputPath, statePath, scopeDomains, scopeRegexp, scopeMode, userAgent string, rps float64, burst, maxConcurrent, maxLinksPerHost, maxHostsPerDomain int, maxBodyBytes int64, outDomainsPath, mirrorDir string, timeout time.Duration) {
+	// A SIGINT/SIGTERM cancels ctx, so in-flight fetches abort and the
+	// pipeline drains instead of being killed mid-write -- see
+	// crawlWorker and processResults, which both check it.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// stop(), above, cancels ctx as part of its own cleanup once crawl
+	// returns normally -- done lets the logging goroutine below tell that
+	// apart from a real interrupt, so a clean run doesn't end with a
+	// misleading "shutdown requested" line.
+	done := make(chan struct{})
+	defer close(done)
+
 	log.Println("Max depth set to", maxDepth)
 	if maxDepth == 0 {
 		log.Println("No max depth specified -- program may not terminate")
@@ -150,34 +485,118 @@ func crawl(baseURL string, maxDepth, maxWorkers int) {
 	}
 	standardizedURL := standardizeURL(urlObj)
 
+	var domainLog io.Writer
+	if outDomainsPath != "" {
+		f, err := os.Create(outDomainsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		domainLog = f
+		log.Println("Streaming newly discovered domains to", outDomainsPath)
+	}
+
+	sc, err := buildScope(urlObj.Hostname(), maxDepth, scopeDomains, scopeRegexp, scopeMode, maxLinksPerHost, maxHostsPerDomain, domainLog)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var store crawlStateStore
+	if statePath != "" {
+		persisted, err := openCrawlState(statePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		store = persisted
+		log.Println("Persisting crawl state to", statePath)
+	} else {
+		store = newMemState()
+	}
+	defer store.Close()
+
+	var warc *warcWriter
+	if outputPath != "" {
+		w, err := newWARCWriter(outputPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		warc = w
+		defer warc.Close()
+		log.Println("Writing WARC archive to", outputPath)
+	}
+
+	if mirrorDir != "" {
+		if err := os.MkdirAll(mirrorDir, 0o755); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Mirroring crawled pages to", mirrorDir)
+	}
+
 	// buffered channel prevents deadlocking, because the results
 	// process and crawling process feed into each other
 	urlsToCrawl := make(chan urlInfo, 1000*maxWorkers)
 	results := make(chan urlResults)
 	var wg sync.WaitGroup
-	// Make sure we wait for the base URL crawl to finish
-	wg.Add(1)
-	// First initialize channel with base URL
-	go func() {
-		urlsToCrawl <- urlInfo{standardizedURL, 1}
-	}()
 
-	go processResults(&wg, urlsToCrawl, results, maxDepth)
+	pending, err := store.LoadFrontier()
+	if err != nil {
+		log.Fatal("Error loading persisted crawl state", err)
+	}
+	if len(pending) > 0 {
+		log.Printf("Resuming %d pending URL(s) from crawl state\n", len(pending))
+		for _, info := range pending {
+			wg.Add(1)
+			go func(info urlInfo) {
+				urlsToCrawl <- info
+			}(info)
+		}
+	} else if crawled, err := store.IsCrawled(standardizedURL); err != nil {
+		log.Fatal(err)
+	} else if crawled {
+		log.Println("Base URL already crawled in a previous run, nothing to resume")
+	} else {
+		// Make sure we wait for the base URL crawl to finish
+		wg.Add(1)
+		if _, err := store.MarkSeen(standardizedURL); err != nil {
+			log.Println("Error marking base URL seen", standardizedURL, err)
+		}
+		store.PutFrontier(urlInfo{standardizedURL, 1})
+		// First initialize channel with base URL
+		go func() {
+			urlsToCrawl <- urlInfo{standardizedURL, 1}
+		}()
+	}
+
+	go processResults(ctx, &wg, urlsToCrawl, results, store, sc)
 
 	// Create custom http client that disables keepalives
 	// to conserve resources
 	tr := &http.Transport{
 		DisableKeepAlives: true,
 	}
-	client := &http.Client{Transport: tr}
+	client := &http.Client{Transport: tr, Timeout: timeout}
+
+	pol := politeness.New(userAgent, client, rps, burst, maxConcurrent)
 
 	// Spawn the appropriate number of crawl workers
 	for i := 0; i < maxWorkers; i++ {
-		go crawlWorker(client, urlsToCrawl, results)
+		go crawlWorker(ctx, client, urlsToCrawl, results, warc, store, pol, maxBodyBytes, sc, mirrorDir, &wg)
 	}
 
+	go func() {
+		select {
+		case <-ctx.Done():
+			log.Println("Shutdown requested, draining in-flight work and flushing state...")
+		case <-done:
+		}
+	}()
+
 	// In the main thread, use wg to detect when there are no more
-	// urls to crawl, then close the channel to stop the workers
+	// urls to crawl, then close the channel to stop the workers. This
+	// still holds after a shutdown signal: every URL is counted done once
+	// it's finished with (success, error, or abort) -- by its worker, or by
+	// processResults for one whose results were handed over -- so
+	// cancellation drains the pipeline instead of hanging.
 	wg.Wait()
 	log.Println("No more URLs to crawl, ending program")
 	close(results)
@@ -188,10 +607,25 @@ func main() {
 	url := flag.String("url", "", "REQUIRED URL to begin parsing")
 	depth := flag.Int("depth", 3, "Max depth for crawling. Set to 0 for no max depth")
 	workers := flag.Int("workers", 0, "Max number of workers in the pool for crawling. A reasonable default will be chosen based on depth setting")
+	output := flag.String("output", "", "Optional path to write a gzip-compressed WARC/1.1 archive of every fetched page")
+	state := flag.String("state", "", "Optional path to a BoltDB file used to persist crawl state, so an interrupted run can be resumed")
+	domains := flag.String("domains", "", "Comma-separated list of domains (and their subdomains) the crawl may follow. Defaults to the seed URL's own host")
+	scopeRegexp := flag.String("scope-regexp", "", "Optional regexp that discovered URLs must also satisfy")
+	scopeMode := flag.String("scope-mode", "and", "How -scope-regexp combines with -domains: \"and\" or \"or\"")
+	userAgent := flag.String("user-agent", "go-webcrawler/1.0", "User-Agent sent with every request, including robots.txt fetches")
+	rps := flag.Float64("rps", 1, "Max requests per second to a single host")
+	burst := flag.Int("burst", 2, "Max burst size for the per-host rate limit")
+	maxConcurrent := flag.Int("max-concurrent", 50, "Max number of requests in flight at once across all hosts")
+	maxLinksPerHost := flag.Int("max-links-per-host", 0, "Max links the crawl will follow on a single host. 0 means unlimited")
+	maxHostsPerDomain := flag.Int("max-hosts-per-domain", 0, "Max distinct hosts the crawl will follow under a single registered domain. 0 means unlimited")
+	maxBodyBytes := flag.Int64("max-body-bytes", 1<<20, "Max response body size to read per page, in bytes")
+	outDomains := flag.String("out-domains", "", "Optional path to write each newly discovered registered domain to, as it's first seen")
+	mirror := flag.String("mirror", "", "Optional directory to write a local, browsable mirror of every crawled page to, rewriting in-scope links to point at it")
+	timeout := flag.Duration("timeout", 15*time.Second, "Per-request timeout for the HTTP client")
 	flag.Parse()
 	if *url == "" {
 		flag.Usage()
 		os.Exit(1)
 	}
-	crawl(*url, *depth, *workers)
+	crawl(*url, *depth, *workers, *output, *state, *domains, *scopeRegexp, *scopeMode, *userAgent, *rps, *burst, *maxConcurrent, *maxLinksPerHost, *maxHostsPerDomain, *maxBodyBytes, *outDomains, *mirror, *timeout)
 }
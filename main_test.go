@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/cjlint/go-webcrawler/politeness"
+	"github.com/cjlint/go-webcrawler/scope"
 	"golang.org/x/net/html"
 )
 
@@ -38,47 +45,114 @@ func TestStandardizeURL(t *testing.T) {
 }
 
 func TestParseURLs(t *testing.T) {
+	base, _ := url.Parse("https://example.com/page")
+
 	tests := []struct {
+		name     string
 		htmlBody string
-		expected []string
+		expected []taggedURL
 	}{
-		{`<a href="https://foo.com">`, []string{"https://foo.com"}},
-		{`<a target="_" x-other-attr="https://bar.com" href="https://foo.com">`,
-			[]string{"https://foo.com"}},
-		{`<a href="  https://foo.com  ">`, []string{"https://foo.com"}},
-		{`<a href="foo.com">`, []string{}},
-		{`<a href="not a url">`, []string{}},
-		{`<a href="http://foo.com">`, []string{}},
-		{`<a href="mailto:me@foo.com">`, []string{}},
-		{`<a href="/relativepath">`, []string{}},
-		{`<nav>
+		{"simple anchor", `<a href="https://foo.com">`,
+			[]taggedURL{{"https://foo.com", scope.TagPrimary}}},
+		{"other attrs ignored", `<a target="_" x-other-attr="https://bar.com" href="https://foo.com">`,
+			[]taggedURL{{"https://foo.com", scope.TagPrimary}}},
+		{"whitespace trimmed", `<a href="  https://foo.com  ">`,
+			[]taggedURL{{"https://foo.com", scope.TagPrimary}}},
+		{"relative href resolved against the page", `<a href="/relativepath">`,
+			[]taggedURL{{"https://example.com/relativepath", scope.TagPrimary}}},
+		{"http is followed, not upgraded to https", `<a href="http://foo.com">`,
+			[]taggedURL{{"http://foo.com", scope.TagPrimary}}},
+		{"mailto is not a crawlable link", `<a href="mailto:me@foo.com">`, nil},
+		{"duplicate anchors are deduped", `<nav>
 			<a href="https://foo.com">
 			<a href="https://foo.com">
-		</nav>`, []string{"https://foo.com"}},
-		{`<nav>
+		</nav>`, []taggedURL{{"https://foo.com", scope.TagPrimary}}},
+		{"multiple distinct anchors", `<nav>
 			<a href="https://foo.com">
 			<a href="https://bar.com">
 		</nav>
-		<a href="https://baz.com">
-		<a href="not a url">`, []string{
-			"https://foo.com", "https://bar.com", "https://baz.com",
+		<a href="https://baz.com">`, []taggedURL{
+			{"https://foo.com", scope.TagPrimary},
+			{"https://bar.com", scope.TagPrimary},
+			{"https://baz.com", scope.TagPrimary},
+		}},
+		{"link/img/script are tagged related", `
+			<link href="https://foo.com/style.css">
+			<img src="https://foo.com/img.png">
+			<script src="https://foo.com/app.js"></script>`, []taggedURL{
+			{"https://foo.com/style.css", scope.TagRelated},
+			{"https://foo.com/img.png", scope.TagRelated},
+			{"https://foo.com/app.js", scope.TagRelated},
 		}},
+		{"css url() in a style tag is tagged related",
+			`<style>body { background: url('https://foo.com/bg.png'); }</style>`,
+			[]taggedURL{{"https://foo.com/bg.png", scope.TagRelated}}},
+		{"css url() in an inline style attribute is tagged related",
+			`<div style="background: url(https://foo.com/bg.png)"></div>`,
+			[]taggedURL{{"https://foo.com/bg.png", scope.TagRelated}}},
 	}
 	for _, tt := range tests {
-		t.Run("Test "+tt.htmlBody, func(t *testing.T) {
+		t.Run(tt.name, func(t *testing.T) {
 			doc, _ := html.Parse(strings.NewReader(tt.htmlBody))
-			result := parseURLs(doc)
-			equal := len(result) == len(tt.expected)
-			for i, expected := range tt.expected {
-				equal = equal && result[i] == expected
-			}
-			if !equal {
+			result := parseURLs(base, doc, nil)
+			if !reflect.DeepEqual(result, tt.expected) {
 				t.Errorf("parseURLs() = %v, expected %v", result, tt.expected)
 			}
 		})
 	}
 }
 
+func TestParseURLsRewrite(t *testing.T) {
+	base, _ := url.Parse("https://example.com/page")
+	rewrite := func(resolved *url.URL, tag scope.LinkTag) string {
+		return "local://" + resolved.Host + resolved.Path
+	}
+
+	tests := []struct {
+		name, htmlBody, wantHTML string
+	}{
+		{"anchor href is rewritten",
+			`<a href="https://foo.com/a">`,
+			`<html><head></head><body><a href="local://foo.com/a"></a></body></html>`},
+		{"img src is rewritten",
+			`<img src="https://foo.com/img.png">`,
+			`<html><head></head><body><img src="local://foo.com/img.png"/></body></html>`},
+		{"css url() in a style tag is rewritten",
+			`<style>body { background: url('https://foo.com/bg.png'); }</style>`,
+			`<html><head><style>body { background: url('local://foo.com/bg.png'); }</style></head><body></body></html>`},
+		{"css url() in an inline style attribute is rewritten",
+			`<div style="background: url(https://foo.com/bg.png)"></div>`,
+			`<html><head></head><body><div style="background: url(local://foo.com/bg.png)"></div></body></html>`},
+		{"mailto href is left untouched",
+			`<a href="mailto:me@foo.com">`,
+			`<html><head></head><body><a href="mailto:me@foo.com"></a></body></html>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := html.Parse(strings.NewReader(tt.htmlBody))
+			parseURLs(base, doc, rewrite)
+
+			var buf strings.Builder
+			if err := html.Render(&buf, doc); err != nil {
+				t.Fatalf("html.Render() error = %v", err)
+			}
+			if buf.String() != tt.wantHTML {
+				t.Errorf("rendered HTML = %v, expected %v", buf.String(), tt.wantHTML)
+			}
+		})
+	}
+}
+
+// primary tags each of urls as a scope.TagPrimary taggedURL, for tests
+// that only care about primary (<a href>) links.
+func primary(urls ...string) []taggedURL {
+	tagged := make([]taggedURL, len(urls))
+	for i, u := range urls {
+		tagged[i] = taggedURL{u, scope.TagPrimary}
+	}
+	return tagged
+}
+
 func TestProcessResults(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -86,8 +160,8 @@ func TestProcessResults(t *testing.T) {
 		expectedOutput []urlInfo
 	}{
 		{"test basic", []urlResults{
-			{"https://foo.com", []string{"https://a.com", "https://b.com"}, 1},
-			{"https://a.com", []string{"https://c.com", "https://d.com"}, 2},
+			{"https://foo.com", primary("https://a.com", "https://b.com"), 1},
+			{"https://a.com", primary("https://c.com", "https://d.com"), 2},
 		}, []urlInfo{
 			{"https://a.com", 2},
 			{"https://b.com", 2},
@@ -95,14 +169,14 @@ func TestProcessResults(t *testing.T) {
 			{"https://d.com", 3},
 		}},
 		{"test ignores already crawled", []urlResults{
-			{"https://foo.com", []string{"https://a.com", "https://foo.com"}, 1},
-			{"https://a.com", []string{"https://a.com", "https://b.com"}, 2},
+			{"https://foo.com", primary("https://a.com", "https://foo.com"), 1},
+			{"https://a.com", primary("https://a.com", "https://b.com"), 2},
 		}, []urlInfo{
 			{"https://a.com", 2},
 			{"https://b.com", 3},
 		}},
 		{"test overflow is ignored", []urlResults{
-			{"https://foo.com", []string{
+			{"https://foo.com", primary(
 				"https://a.com",
 				"https://b.com",
 				"https://c.com",
@@ -110,7 +184,7 @@ func TestProcessResults(t *testing.T) {
 				"https://e.com",
 				"https://f.com",
 				"https://g.com",
-			}, 1},
+			), 1},
 		}, []urlInfo{
 			{"https://a.com", 2},
 			{"https://b.com", 2},
@@ -119,8 +193,19 @@ func TestProcessResults(t *testing.T) {
 			{"https://e.com", 2},
 		}},
 		{"test max depth is ignored", []urlResults{
-			{"https://foo.com", []string{"https://a.com", "https://b.com"}, 3},
+			{"https://foo.com", primary("https://a.com", "https://b.com"), 3},
 		}, []urlInfo{}},
+		{"test related links don't consume a depth level", []urlResults{
+			{"https://foo.com", []taggedURL{{"https://a.com", scope.TagRelated}}, 3},
+		}, []urlInfo{
+			{"https://a.com", 3},
+		}},
+		{"test same child linked from two parents is only enqueued once", []urlResults{
+			{"https://foo.com", primary("https://a.com"), 1},
+			{"https://bar.com", primary("https://a.com"), 1},
+		}, []urlInfo{
+			{"https://a.com", 2},
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -128,7 +213,7 @@ func TestProcessResults(t *testing.T) {
 			urlsToCrawl := make(chan urlInfo, 5)
 			results := make(chan urlResults)
 			wg.Add(1)
-			go processResults(&wg, urlsToCrawl, results, 3)
+			go processResults(context.Background(), &wg, urlsToCrawl, results, newMemState(), scope.DepthScope{Max: 3})
 
 			for _, result := range tt.resultsInput {
 				results <- result
@@ -155,3 +240,56 @@ func TestProcessResults(t *testing.T) {
 		})
 	}
 }
+
+// TestCrawlWorkerArchivesNonHTMLAssets guards against the HEAD precheck
+// skipping related (non-HTML) assets when WARC archiving or mirroring is
+// active: both need the asset's actual bytes, not just a "this is HTML"
+// filter meant to save bandwidth when simply following links.
+func TestCrawlWorkerArchivesNonHTMLAssets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("not-really-a-png"))
+	}))
+	defer srv.Close()
+	assetURL := srv.URL + "/img.png"
+
+	warcPath := filepath.Join(t.TempDir(), "archive.warc.gz")
+	warc, err := newWARCWriter(warcPath)
+	if err != nil {
+		t.Fatalf("newWARCWriter() error = %v", err)
+	}
+
+	urlsToCrawl := make(chan urlInfo, 1)
+	results := make(chan urlResults, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	urlsToCrawl <- urlInfo{assetURL, 1}
+	close(urlsToCrawl)
+
+	pol := politeness.New("go-webcrawler/1.0", srv.Client(), 1000, 1000, 10)
+	crawlWorker(context.Background(), srv.Client(), urlsToCrawl, results, warc, newMemState(), pol, 1<<20, scope.DepthScope{Max: 3}, "", &wg)
+
+	if err := warc.Close(); err != nil {
+		t.Fatalf("warc.Close() error = %v", err)
+	}
+
+	select {
+	case r := <-results:
+		if r.baseURL != assetURL {
+			t.Errorf("got result for %q, expected %q", r.baseURL, assetURL)
+		}
+	default:
+		t.Fatalf("expected the image asset to be fetched and sent to results, but it was skipped")
+	}
+
+	records := readWARCRecords(t, warcPath)
+	found := false
+	for _, rec := range records {
+		if strings.Contains(rec, "WARC-Target-URI: "+assetURL) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a WARC record for %s, records:\n%v", assetURL, records)
+	}
+}
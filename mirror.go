@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// mirrorPath returns the path, relative to a mirror's root directory, that
+// a page fetched from u should be written to: host/path/index.html. A
+// trailing slash on the path (or no path at all) collapses into the
+// index.html itself, so https://example.com/blog/ and
+// https://example.com/blog both land at example.com/blog/index.html.
+//
+// "." and ".." path segments are dropped rather than joined in verbatim: a
+// crawled page is untrusted input, and a percent-encoded ".." (which
+// survives Go's dot-segment collapsing, since that operates on the escaped
+// path) would otherwise let filepath.Join walk the write outside dir.
+func mirrorPath(u *url.URL) string {
+	segments := []string{u.Hostname()}
+	if trimmed := strings.Trim(u.Path, "/"); trimmed != "" {
+		for _, seg := range strings.Split(trimmed, "/") {
+			if seg == "" || seg == "." || seg == ".." {
+				continue
+			}
+			segments = append(segments, seg)
+		}
+	}
+	segments = append(segments, "index.html")
+	return filepath.Join(segments...)
+}
+
+// relativeMirrorPath returns the relative path, suitable for use as an
+// href, from the mirrored copy of fromURL to the mirrored copy of toURL.
+func relativeMirrorPath(fromURL, toURL *url.URL) (string, error) {
+	fromDir := filepath.Dir(mirrorPath(fromURL))
+	rel, err := filepath.Rel(fromDir, mirrorPath(toURL))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// writeMirrorPage serializes doc, as mirrorPath(pageURL) underneath dir,
+// creating any intermediate directories.
+func writeMirrorPage(dir string, pageURL *url.URL, doc *html.Node) error {
+	path := filepath.Join(dir, mirrorPath(pageURL))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return html.Render(f, doc)
+}
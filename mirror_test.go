@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestMirrorPath(t *testing.T) {
+	tests := []struct {
+		url, expected string
+	}{
+		{"https://example.com", "example.com/index.html"},
+		{"https://example.com/", "example.com/index.html"},
+		{"https://example.com/blog", "example.com/blog/index.html"},
+		{"https://example.com/blog/", "example.com/blog/index.html"},
+		{"https://example.com/blog/post", "example.com/blog/post/index.html"},
+		// A percent-encoded ".." survives Go's dot-segment collapsing
+		// (which runs on the escaped path), so it can reach here as a
+		// literal ".." segment straight from a crawled page's href.
+		{"https://example.com/blog/../../../escaped/pwned", "example.com/blog/escaped/pwned/index.html"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := mirrorPath(u); got != tt.expected {
+			t.Errorf("mirrorPath(%s) = %v, expected %v", tt.url, got, tt.expected)
+		}
+	}
+}
+
+// TestWriteMirrorPageRejectsPathTraversal guards against a page containing
+// a percent-encoded "../" href writing outside the mirror directory -- see
+// mirrorPath's doc comment.
+func TestWriteMirrorPageRejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	base, _ := url.Parse("https://example.com/blog/")
+	rel, _ := url.Parse("%2e%2e/%2e%2e/%2e%2e/escaped/pwned")
+	resolved := base.ResolveReference(rel)
+	if !strings.Contains(resolved.Path, "..") {
+		t.Fatalf("test setup broken: resolved path %q has no literal \"..\"", resolved.Path)
+	}
+
+	doc, _ := html.Parse(strings.NewReader("<html><body>pwned</body></html>"))
+	if err := writeMirrorPage(dir, resolved, doc); err != nil {
+		t.Fatalf("writeMirrorPage() error = %v", err)
+	}
+
+	escaped := filepath.Join(filepath.Dir(dir), "escaped", "pwned", "index.html")
+	if _, err := os.Stat(escaped); err == nil {
+		t.Errorf("writeMirrorPage() escaped the mirror directory, wrote %s", escaped)
+	}
+
+	inside := filepath.Join(dir, "example.com", "blog", "escaped", "pwned", "index.html")
+	if _, err := os.Stat(inside); err != nil {
+		t.Errorf("expected mirrored page inside dir at %s: %v", inside, err)
+	}
+}
+
+func TestRelativeMirrorPath(t *testing.T) {
+	tests := []struct {
+		from, to, expected string
+	}{
+		{"https://example.com/blog/", "https://example.com/blog/post", "post/index.html"},
+		{"https://example.com/blog/post", "https://example.com/blog/", "../index.html"},
+		{"https://example.com/blog/post", "https://example.com/about", "../../about/index.html"},
+		{"https://example.com/", "https://example.com/", "index.html"},
+	}
+	for _, tt := range tests {
+		from, _ := url.Parse(tt.from)
+		to, _ := url.Parse(tt.to)
+		got, err := relativeMirrorPath(from, to)
+		if err != nil {
+			t.Fatalf("relativeMirrorPath(%s, %s) error = %v", tt.from, tt.to, err)
+		}
+		if got != tt.expected {
+			t.Errorf("relativeMirrorPath(%s, %s) = %v, expected %v", tt.from, tt.to, got, tt.expected)
+		}
+	}
+}
+
+func TestWriteMirrorPage(t *testing.T) {
+	dir := t.TempDir()
+	u, _ := url.Parse("https://example.com/blog/post")
+	doc, _ := html.Parse(strings.NewReader("<html><body>hi</body></html>"))
+
+	if err := writeMirrorPage(dir, u, doc); err != nil {
+		t.Fatalf("writeMirrorPage() error = %v", err)
+	}
+
+	path := filepath.Join(dir, "example.com", "blog", "post", "index.html")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected mirrored page at %s: %v", path, err)
+	}
+	if !strings.Contains(string(data), "hi") {
+		t.Errorf("mirrored page missing expected content: %s", data)
+	}
+}
@@ -0,0 +1,106 @@
+package politeness
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Policy enforces robots.txt compliance, a per-host token-bucket rate
+// limit, and a global concurrent-request cap. Workers call Allowed before
+// fetching a URL and Wait before issuing the request, and must call
+// Release once that request completes.
+type Policy struct {
+	robots *RobotsCache
+
+	rps   float64
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+
+	sem chan struct{}
+}
+
+// New builds a Policy. rps and burst configure the per-host token bucket;
+// maxConcurrent bounds how many fetches may be in flight at once across
+// every host. robots.txt is fetched using userAgent and client.
+func New(userAgent string, client *http.Client, rps float64, burst, maxConcurrent int) *Policy {
+	return &Policy{
+		robots:   NewRobotsCache(userAgent, client),
+		rps:      rps,
+		burst:    burst,
+		limiters: map[string]*rate.Limiter{},
+		sem:      make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Allowed reports whether u may be fetched under its host's robots.txt
+// rules, fetching and caching that host's robots.txt on first use.
+func (p *Policy) Allowed(ctx context.Context, u *url.URL) bool {
+	return p.robots.Allowed(ctx, u)
+}
+
+// Wait blocks until host's token bucket has a token available and a
+// global concurrency slot is free, or until ctx is done. A host's
+// robots.txt Crawl-delay, if any, is honored by slowing that host's
+// bucket to at least one token per delay. Every successful Wait must be
+// paired with a call to Release once the fetch completes.
+//
+// The host limiter is waited on before the global semaphore is acquired,
+// not after: a slow host's token bucket can make a caller wait
+// indefinitely, and doing that wait while holding a global slot would let
+// one slow host starve every other host of concurrency.
+func (p *Policy) Wait(ctx context.Context, u *url.URL) error {
+	if err := p.hostLimiter(ctx, u).Wait(ctx); err != nil {
+		return err
+	}
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Release frees the concurrency slot acquired by a successful Wait.
+func (p *Policy) Release() {
+	<-p.sem
+}
+
+func (p *Policy) hostLimiter(ctx context.Context, u *url.URL) *rate.Limiter {
+	host := u.Host
+
+	p.mu.Lock()
+	l, ok := p.limiters[host]
+	p.mu.Unlock()
+	if ok {
+		return l
+	}
+
+	// CrawlDelay may do a synchronous HTTP fetch of this host's robots.txt
+	// on a cache miss, so it must run without holding mu: every other
+	// host's Wait call takes the same lock, and would otherwise stall for
+	// as long as this one host's fetch does (up to -timeout).
+	limit := rate.Limit(p.rps)
+	burst := p.burst
+	if delay := p.robots.CrawlDelay(ctx, u); delay > 0 {
+		if perSecond := rate.Every(delay); perSecond < limit {
+			limit, burst = perSecond, 1
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if existing, ok := p.limiters[host]; ok {
+		// Lost a race with another worker building this host's limiter
+		// concurrently; use theirs so later Wait calls all share one bucket.
+		return existing
+	}
+	l = rate.NewLimiter(limit, burst)
+	p.limiters[host] = l
+	return l
+}
@@ -0,0 +1,126 @@
+package politeness
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestPolicyAllowedUsesRobots(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer srv.Close()
+
+	p := New("go-webcrawler/1.0", srv.Client(), 100, 100, 10)
+
+	allowed, _ := url.Parse(srv.URL + "/public")
+	disallowed, _ := url.Parse(srv.URL + "/private")
+	if !p.Allowed(context.Background(), allowed) {
+		t.Errorf("expected /public to be allowed")
+	}
+	if p.Allowed(context.Background(), disallowed) {
+		t.Errorf("expected /private to be disallowed")
+	}
+}
+
+func TestPolicyWaitEnforcesConcurrencyCap(t *testing.T) {
+	p := New("go-webcrawler/1.0", http.DefaultClient, 1000, 1000, 1)
+	u, _ := url.Parse("https://foo.com")
+
+	if err := p.Wait(context.Background(), u); err != nil {
+		t.Fatalf("first Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Wait(ctx, u); err == nil {
+		t.Errorf("expected second Wait() to block on the concurrency cap until ctx expired")
+	}
+
+	p.Release()
+	if err := p.Wait(context.Background(), u); err != nil {
+		t.Errorf("Wait() after Release() error = %v", err)
+	}
+}
+
+func TestPolicyWaitSlowHostDoesNotStarveOtherHosts(t *testing.T) {
+	p := New("go-webcrawler/1.0", http.DefaultClient, 0.001, 1, 1)
+	a, _ := url.Parse("https://a.com")
+	b, _ := url.Parse("https://b.com")
+
+	// Burn a.com's single burst token, then free the concurrency slot.
+	if err := p.Wait(context.Background(), a); err != nil {
+		t.Fatalf("first Wait(a) error = %v", err)
+	}
+	p.Release()
+
+	// a.com's next Wait will now block on its rate limiter for a long
+	// time (rps=0.001). It's given a context with no deadline, so rate's
+	// Wait actually blocks rather than failing fast on a too-long
+	// reservation -- if the global concurrency slot were held while
+	// waiting on a host limiter, b.com -- an unrelated, unthrottled host
+	// -- would be starved out of the crawl's only concurrency slot too.
+	go p.Wait(context.Background(), a)
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := p.Wait(ctx, b); err != nil {
+		t.Errorf("expected b.com's Wait() to succeed promptly instead of being starved by a.com's rate limit: %v", err)
+	}
+}
+
+func TestPolicyWaitSlowRobotsFetchDoesNotBlockOtherHosts(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("User-agent: *\n"))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\n"))
+	}))
+	defer fast.Close()
+
+	p := New("go-webcrawler/1.0", http.DefaultClient, 1000, 1000, 10)
+	slowURL, _ := url.Parse(slow.URL)
+	fastURL, _ := url.Parse(fast.URL)
+
+	done := make(chan struct{})
+	go func() {
+		p.Wait(context.Background(), slowURL)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond) // let the slow host's Wait start its robots.txt fetch
+
+	// Every host's Wait shares Policy.mu to build its rate.Limiter, so if
+	// that lock were held across the slow host's robots.txt fetch, the
+	// fast, unrelated host below would be stuck waiting on it too.
+	start := time.Now()
+	if err := p.Wait(context.Background(), fastURL); err != nil {
+		t.Fatalf("Wait(fast) error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Wait(fast) took %v, expected it to return promptly instead of waiting on the slow host's robots.txt fetch", elapsed)
+	}
+	<-done
+}
+
+func TestPolicyWaitRateLimitsPerHost(t *testing.T) {
+	p := New("go-webcrawler/1.0", http.DefaultClient, 1, 1, 10)
+	u, _ := url.Parse("https://foo.com")
+
+	if err := p.Wait(context.Background(), u); err != nil {
+		t.Fatalf("first Wait() error = %v", err)
+	}
+	p.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Wait(ctx, u); err == nil {
+		t.Errorf("expected the second request within the same second to be rate-limited")
+	}
+}
@@ -0,0 +1,196 @@
+// Package politeness sits between the crawl frontier and the workers that
+// fetch it, enforcing robots.txt and per-host rate limits so the crawler
+// behaves as a well-behaved citizen instead of hammering a single host
+// with every worker at once.
+package politeness
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// robotsRules is the parsed result of a robots.txt file for the group
+// (User-agent block) that applies to us: the most specific group whose
+// User-agent is a substring of ours, or the wildcard "*" group otherwise.
+type robotsRules struct {
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+}
+
+// allows reports whether path may be fetched, using the Robots Exclusion
+// Protocol's longest-match-wins rule: the most specific (longest)
+// matching Allow/Disallow rule takes precedence.
+func (r robotsRules) allows(path string) bool {
+	if path == "" {
+		// *url.URL.Path is "" for bare-root URLs (e.g. "https://example.com"),
+		// but robots.txt rules are written against "/" -- without this, the
+		// single most common directive, "Disallow: /", would never match.
+		path = "/"
+	}
+	bestMatch := ""
+	allowed := true
+	consider := func(rule string, ruleAllowed bool) {
+		if rule == "" || !strings.HasPrefix(path, rule) || len(rule) <= len(bestMatch) {
+			return
+		}
+		bestMatch = rule
+		allowed = ruleAllowed
+	}
+	for _, d := range r.disallow {
+		consider(d, false)
+	}
+	for _, a := range r.allow {
+		consider(a, true)
+	}
+	return allowed
+}
+
+// parseRobots reads a robots.txt document and returns the rules that
+// apply to userAgent.
+func parseRobots(body io.Reader, userAgent string) robotsRules {
+	groups := map[string]*robotsRules{}
+	var currentAgents []string
+	inAgentLine := true
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		switch field {
+		case "user-agent":
+			agent := strings.ToLower(value)
+			if !inAgentLine {
+				// A fresh User-agent line after rules started a new group.
+				currentAgents = nil
+			}
+			currentAgents = append(currentAgents, agent)
+			if groups[agent] == nil {
+				groups[agent] = &robotsRules{}
+			}
+			inAgentLine = true
+		case "allow", "disallow":
+			inAgentLine = false
+			for _, agent := range currentAgents {
+				if field == "allow" {
+					groups[agent].allow = append(groups[agent].allow, value)
+				} else {
+					groups[agent].disallow = append(groups[agent].disallow, value)
+				}
+			}
+		case "crawl-delay":
+			inAgentLine = false
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			for _, agent := range currentAgents {
+				groups[agent].crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	ua := strings.ToLower(userAgent)
+	bestAgent, bestRules := "", (*robotsRules)(nil)
+	for agent, rules := range groups {
+		if agent != "*" && strings.Contains(ua, agent) && len(agent) > len(bestAgent) {
+			bestAgent, bestRules = agent, rules
+		}
+	}
+	if bestRules != nil {
+		return *bestRules
+	}
+	if rules, ok := groups["*"]; ok {
+		return *rules
+	}
+	return robotsRules{}
+}
+
+func stripComment(line string) string {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+// RobotsCache fetches and caches robots.txt on a per-host basis, so once a
+// host's rules are cached they aren't fetched again. Lookups for a host
+// that miss the cache at the same time each fetch its robots.txt.
+type RobotsCache struct {
+	userAgent string
+	client    *http.Client
+
+	mu    sync.Mutex
+	rules map[string]robotsRules
+}
+
+// NewRobotsCache builds a RobotsCache that identifies itself as userAgent
+// when fetching robots.txt, using client to make the request.
+func NewRobotsCache(userAgent string, client *http.Client) *RobotsCache {
+	return &RobotsCache{userAgent: userAgent, client: client, rules: map[string]robotsRules{}}
+}
+
+// Allowed reports whether u may be fetched under its host's robots.txt.
+// A robots.txt that can't be fetched (including a 404) is treated as
+// allow-all, per the usual Robots Exclusion Protocol convention.
+func (c *RobotsCache) Allowed(ctx context.Context, u *url.URL) bool {
+	return c.rulesFor(ctx, u).allows(u.Path)
+}
+
+// CrawlDelay returns u's host's Crawl-delay directive, or zero if none
+// was specified.
+func (c *RobotsCache) CrawlDelay(ctx context.Context, u *url.URL) time.Duration {
+	return c.rulesFor(ctx, u).crawlDelay
+}
+
+func (c *RobotsCache) rulesFor(ctx context.Context, u *url.URL) robotsRules {
+	host := u.Host
+
+	c.mu.Lock()
+	rules, ok := c.rules[host]
+	c.mu.Unlock()
+	if ok {
+		return rules
+	}
+
+	rules = c.fetch(ctx, u.Scheme, host)
+
+	c.mu.Lock()
+	c.rules[host] = rules
+	c.mu.Unlock()
+	return rules
+}
+
+func (c *RobotsCache) fetch(ctx context.Context, scheme, host string) robotsRules {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+"/robots.txt", nil)
+	if err != nil {
+		return robotsRules{}
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return robotsRules{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return robotsRules{}
+	}
+	return parseRobots(resp.Body, c.userAgent)
+}
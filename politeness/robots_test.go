@@ -0,0 +1,106 @@
+package politeness
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRobotsRulesAllows(t *testing.T) {
+	rules := robotsRules{
+		disallow: []string{"/private"},
+		allow:    []string{"/private/public"},
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"/private", false},
+		{"/private/secret", false},
+		{"/private/public", true},
+		{"/private/public/page", true},
+	}
+	for _, tt := range tests {
+		if got := rules.allows(tt.path); got != tt.want {
+			t.Errorf("allows(%q) = %v, expected %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsRulesAllowsEmptyPathMeansRoot(t *testing.T) {
+	rules := robotsRules{disallow: []string{"/"}}
+	if rules.allows("") {
+		t.Error("allows(\"\") = true, expected false for Disallow: / (bare-root URL.Path is empty)")
+	}
+}
+
+func TestParseRobotsPicksMostSpecificUserAgent(t *testing.T) {
+	body := strings.NewReader(`
+User-agent: *
+Disallow: /
+
+User-agent: go-webcrawler
+Allow: /
+Disallow: /admin
+Crawl-delay: 2
+`)
+	rules := parseRobots(body, "go-webcrawler/1.0")
+	if !rules.allows("/") || rules.allows("/admin") {
+		t.Errorf("expected the go-webcrawler group to apply, got %+v", rules)
+	}
+	if rules.crawlDelay != 2*time.Second {
+		t.Errorf("crawlDelay = %v, expected 2s", rules.crawlDelay)
+	}
+}
+
+func TestParseRobotsFallsBackToWildcard(t *testing.T) {
+	body := strings.NewReader(`
+User-agent: *
+Disallow: /private
+`)
+	rules := parseRobots(body, "go-webcrawler/1.0")
+	if rules.allows("/private") || !rules.allows("/public") {
+		t.Errorf("expected the wildcard group to apply, got %+v", rules)
+	}
+}
+
+func TestRobotsCacheFetchesAndCaches(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL + "/private/page")
+	cache := NewRobotsCache("go-webcrawler/1.0", srv.Client())
+
+	if cache.Allowed(context.Background(), u) {
+		t.Errorf("expected /private/page to be disallowed")
+	}
+	if requests != 1 {
+		t.Fatalf("expected robots.txt to be fetched once, got %d requests", requests)
+	}
+	cache.Allowed(context.Background(), u)
+	if requests != 1 {
+		t.Errorf("expected a cached result on the second call, got %d requests", requests)
+	}
+}
+
+func TestRobotsCacheMissingFileAllowsAll(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL + "/anything")
+	cache := NewRobotsCache("go-webcrawler/1.0", srv.Client())
+	if !cache.Allowed(context.Background(), u) {
+		t.Errorf("expected a missing robots.txt to allow everything")
+	}
+}
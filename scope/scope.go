@@ -0,0 +1,237 @@
+// Package scope defines policies for deciding whether a crawler should
+// follow a link it discovered, plus a handful of composable, built-in
+// policies covering the common cases: stay on one host, stay within a set
+// of domains, match a URL pattern, bound crawl depth, cap how many
+// distinct hosts a single domain may contribute, cap how many links a
+// single host may contribute, and log newly discovered domains.
+package scope
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// LinkTag classifies how a link was discovered on a page. Primary links
+// are the page's own outbound navigation (<a href>); related links are
+// the assets a page needs to render (images, stylesheets, scripts) and,
+// unlike primary links, don't advance the crawl depth.
+type LinkTag int
+
+const (
+	TagPrimary LinkTag = iota
+	TagRelated
+)
+
+func (t LinkTag) String() string {
+	if t == TagRelated {
+		return "related"
+	}
+	return "primary"
+}
+
+// Scope decides whether a discovered link should be added to the crawl
+// frontier. depth is the depth the link would be enqueued at, already
+// accounting for LinkTag (see [LinkTag]).
+type Scope interface {
+	Check(u *url.URL, depth int, tag LinkTag) bool
+}
+
+// ScopeFunc adapts a plain function to the Scope interface.
+type ScopeFunc func(u *url.URL, depth int, tag LinkTag) bool
+
+// Check implements Scope.
+func (f ScopeFunc) Check(u *url.URL, depth int, tag LinkTag) bool { return f(u, depth, tag) }
+
+// And combines scopes so a link must satisfy all of them. And() with no
+// scopes always allows.
+func And(scopes ...Scope) Scope {
+	return ScopeFunc(func(u *url.URL, depth int, tag LinkTag) bool {
+		for _, s := range scopes {
+			if !s.Check(u, depth, tag) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Or combines scopes so a link only needs to satisfy one of them. Or()
+// with no scopes never allows.
+func Or(scopes ...Scope) Scope {
+	return ScopeFunc(func(u *url.URL, depth int, tag LinkTag) bool {
+		for _, s := range scopes {
+			if s.Check(u, depth, tag) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// SeedHostScope restricts the crawl to a single, exact hostname.
+type SeedHostScope struct {
+	Host string
+}
+
+// Check implements Scope.
+func (s SeedHostScope) Check(u *url.URL, depth int, tag LinkTag) bool {
+	return strings.EqualFold(u.Hostname(), s.Host)
+}
+
+// DomainScope allows a hostname and any of its subdomains.
+type DomainScope struct {
+	Domain string
+}
+
+// Check implements Scope.
+func (s DomainScope) Check(u *url.URL, depth int, tag LinkTag) bool {
+	host := strings.ToLower(u.Hostname())
+	domain := strings.ToLower(s.Domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// RegexpScope allows any URL whose string form matches Pattern.
+type RegexpScope struct {
+	Pattern *regexp.Regexp
+}
+
+// Check implements Scope.
+func (s RegexpScope) Check(u *url.URL, depth int, tag LinkTag) bool {
+	return s.Pattern.MatchString(u.String())
+}
+
+// DepthScope bounds how deep primary links may go. Related links are
+// exempt, since they're the current page's own assets rather than a
+// further hop out. Max == 0 means unlimited depth.
+type DepthScope struct {
+	Max int
+}
+
+// Check implements Scope.
+func (s DepthScope) Check(u *url.URL, depth int, tag LinkTag) bool {
+	if s.Max == 0 || tag == TagRelated {
+		return true
+	}
+	return depth <= s.Max
+}
+
+// MaxHostsPerDomainScope caps how many distinct hosts under the same
+// registered domain the crawl will follow, so a subdomain farm (e.g.
+// *.blogspot.com) can't dominate the frontier. It is safe for concurrent
+// use, since the same Scope instance is shared by every crawl worker.
+type MaxHostsPerDomainScope struct {
+	Max int
+
+	mu    sync.Mutex
+	hosts map[string]map[string]bool
+}
+
+// Check implements Scope.
+func (s *MaxHostsPerDomainScope) Check(u *url.URL, depth int, tag LinkTag) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hosts == nil {
+		s.hosts = map[string]map[string]bool{}
+	}
+
+	domain := registeredDomain(u.Hostname())
+	host := strings.ToLower(u.Hostname())
+	seen := s.hosts[domain]
+	if seen[host] {
+		return true
+	}
+	if len(seen) >= s.Max {
+		return false
+	}
+	if seen == nil {
+		seen = map[string]bool{}
+		s.hosts[domain] = seen
+	}
+	seen[host] = true
+	return true
+}
+
+// MaxLinksPerHostScope caps how many links belonging to the same host the
+// crawl will accept, regardless of how many distinct hosts are involved --
+// useful for throttling a single pathological page (e.g. an infinite
+// calendar) that links to thousands of URLs on its own host. It is safe
+// for concurrent use, since the same Scope instance is shared by every
+// crawl worker.
+type MaxLinksPerHostScope struct {
+	Max int
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Check implements Scope.
+func (s *MaxLinksPerHostScope) Check(u *url.URL, depth int, tag LinkTag) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts == nil {
+		s.counts = map[string]int{}
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if s.counts[host] >= s.Max {
+		return false
+	}
+	s.counts[host]++
+	return true
+}
+
+// DomainLogger is a Scope that always allows a link, but as a side effect
+// writes each newly discovered registered domain to Writer, once, the
+// first time a link in that domain passes Check. It's meant to sit last
+// in an And() chain so only domains the crawl actually follows get
+// logged. It is safe for concurrent use.
+type DomainLogger struct {
+	Writer io.Writer
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// Check implements Scope.
+func (d *DomainLogger) Check(u *url.URL, depth int, tag LinkTag) bool {
+	domain := registeredDomain(u.Hostname())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen == nil {
+		d.seen = map[string]bool{}
+	}
+	if !d.seen[domain] {
+		d.seen[domain] = true
+		fmt.Fprintln(d.Writer, domain)
+	}
+	return true
+}
+
+// registeredDomain returns a host's registrable domain, e.g.
+// "a.b.example.com" and "foo.co.uk" become "example.com" and "foo.co.uk"
+// respectively. Hosts under a PRIVATE-section PSL entry (e.g.
+// "a.blogspot.com", where ICANN delegates "blogspot.com" itself to
+// Blogger to hand out to its own users) are deliberately *not* split
+// apart by owner: those are exactly the subdomain farms this package
+// exists to group together, so such hosts fall back to a naive
+// last-two-labels heuristic instead.
+func registeredDomain(host string) string {
+	host = strings.ToLower(host)
+	if _, icann := publicsuffix.PublicSuffix(host); icann {
+		if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+			return domain
+		}
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) <= 2 {
+		return host
+	}
+	return strings.Join(labels[len(labels)-2:], ".")
+}
@@ -0,0 +1,153 @@
+package scope
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) error = %v", raw, err)
+	}
+	return u
+}
+
+func TestSeedHostScope(t *testing.T) {
+	s := SeedHostScope{Host: "foo.com"}
+	if !s.Check(mustParse(t, "https://foo.com/a"), 1, TagPrimary) {
+		t.Errorf("expected same host to be in scope")
+	}
+	if s.Check(mustParse(t, "https://bar.com/a"), 1, TagPrimary) {
+		t.Errorf("expected different host to be out of scope")
+	}
+}
+
+func TestDomainScope(t *testing.T) {
+	s := DomainScope{Domain: "foo.com"}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://foo.com", true},
+		{"https://www.foo.com", true},
+		{"https://evilfoo.com", false},
+		{"https://bar.com", false},
+	}
+	for _, tt := range tests {
+		if got := s.Check(mustParse(t, tt.url), 1, TagPrimary); got != tt.want {
+			t.Errorf("Check(%s) = %v, expected %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRegexpScope(t *testing.T) {
+	s := RegexpScope{Pattern: regexp.MustCompile(`/blog/\d+$`)}
+	if !s.Check(mustParse(t, "https://foo.com/blog/123"), 1, TagPrimary) {
+		t.Errorf("expected matching path to be in scope")
+	}
+	if s.Check(mustParse(t, "https://foo.com/about"), 1, TagPrimary) {
+		t.Errorf("expected non-matching path to be out of scope")
+	}
+}
+
+func TestDepthScope(t *testing.T) {
+	s := DepthScope{Max: 2}
+	u := mustParse(t, "https://foo.com")
+	if !s.Check(u, 2, TagPrimary) {
+		t.Errorf("expected depth at the max to be in scope")
+	}
+	if s.Check(u, 3, TagPrimary) {
+		t.Errorf("expected depth beyond the max to be out of scope")
+	}
+	if !s.Check(u, 3, TagRelated) {
+		t.Errorf("expected a related link to ignore the depth bound")
+	}
+}
+
+func TestMaxHostsPerDomainScope(t *testing.T) {
+	s := &MaxHostsPerDomainScope{Max: 2}
+	urls := []string{
+		"https://a.blogspot.com",
+		"https://b.blogspot.com",
+		"https://a.blogspot.com", // repeat of an already-allowed host
+		"https://c.blogspot.com", // third distinct host, over the cap
+	}
+	want := []bool{true, true, true, false}
+	for i, raw := range urls {
+		if got := s.Check(mustParse(t, raw), 1, TagPrimary); got != want[i] {
+			t.Errorf("Check(%s) = %v, expected %v", raw, got, want[i])
+		}
+	}
+}
+
+func TestMaxHostsPerDomainScopeUsesRegisteredDomain(t *testing.T) {
+	s := &MaxHostsPerDomainScope{Max: 2}
+	urls := []string{
+		"https://a.example.co.uk",
+		"https://b.example.co.uk",
+		"https://c.example.co.uk", // same registered domain (example.co.uk), over the cap
+		"https://a.other.co.uk",   // different registered domain entirely, unaffected
+	}
+	want := []bool{true, true, false, true}
+	for i, raw := range urls {
+		if got := s.Check(mustParse(t, raw), 1, TagPrimary); got != want[i] {
+			t.Errorf("Check(%s) = %v, expected %v", raw, got, want[i])
+		}
+	}
+}
+
+func TestMaxLinksPerHostScope(t *testing.T) {
+	s := &MaxLinksPerHostScope{Max: 2}
+	urls := []string{
+		"https://foo.com/a",
+		"https://foo.com/b",
+		"https://foo.com/c", // third link from this host, over the cap
+		"https://bar.com/a", // different host, unaffected
+	}
+	want := []bool{true, true, false, true}
+	for i, raw := range urls {
+		if got := s.Check(mustParse(t, raw), 1, TagPrimary); got != want[i] {
+			t.Errorf("Check(%s) = %v, expected %v", raw, got, want[i])
+		}
+	}
+}
+
+func TestDomainLoggerLogsEachDomainOnce(t *testing.T) {
+	var buf strings.Builder
+	d := &DomainLogger{Writer: &buf}
+
+	d.Check(mustParse(t, "https://a.blogspot.com"), 1, TagPrimary)
+	d.Check(mustParse(t, "https://b.blogspot.com"), 1, TagPrimary)
+	d.Check(mustParse(t, "https://foo.com"), 1, TagPrimary)
+
+	got := buf.String()
+	want := "blogspot.com\nfoo.com\n"
+	if got != want {
+		t.Errorf("logged domains = %q, expected %q", got, want)
+	}
+}
+
+func TestAndOr(t *testing.T) {
+	domain := DomainScope{Domain: "foo.com"}
+	re := RegexpScope{Pattern: regexp.MustCompile(`/blog/`)}
+
+	and := And(domain, re)
+	or := Or(domain, re)
+
+	inDomainOnly := mustParse(t, "https://foo.com/about")
+	inRegexpOnly := mustParse(t, "https://other.com/blog/123")
+
+	if and.Check(inDomainOnly, 1, TagPrimary) {
+		t.Errorf("And() should require both scopes to match")
+	}
+	if !or.Check(inDomainOnly, 1, TagPrimary) {
+		t.Errorf("Or() should allow a URL matching only one scope")
+	}
+	if !or.Check(inRegexpOnly, 1, TagPrimary) {
+		t.Errorf("Or() should allow a URL matching only the other scope")
+	}
+}
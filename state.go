@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	seenBucket     = []byte("seen")
+	crawledBucket  = []byte("crawled")
+	frontierBucket = []byte("frontier")
+)
+
+// crawlStateStore is the seen/crawled/frontier bookkeeping that
+// processResults needs. It's implemented by crawlState (persisted to disk
+// via BoltDB, for resumable crawls started with -state) and by memState
+// (plain in-memory maps, used when -state is omitted).
+type crawlStateStore interface {
+	MarkSeen(url string) (isNew bool, err error)
+	MarkCrawled(url string) error
+	IsCrawled(url string) (bool, error)
+	PutFrontier(info urlInfo) error
+	RemoveFrontier(url string) error
+	LoadFrontier() ([]urlInfo, error)
+	Close() error
+}
+
+// memState is the in-memory crawlStateStore used when no -state path is
+// given. It has no frontier persistence, so LoadFrontier always returns
+// nothing -- matching the crawler's previous non-resumable behavior.
+type memState struct {
+	mu      sync.Mutex
+	seen    map[string]bool
+	crawled map[string]bool
+}
+
+func newMemState() *memState {
+	return &memState{seen: map[string]bool{}, crawled: map[string]bool{}}
+}
+
+func (s *memState) MarkSeen(url string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[url] {
+		return false, nil
+	}
+	s.seen[url] = true
+	return true, nil
+}
+
+func (s *memState) MarkCrawled(url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crawled[url] = true
+	return nil
+}
+
+func (s *memState) IsCrawled(url string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.crawled[url], nil
+}
+
+func (s *memState) PutFrontier(info urlInfo) error   { return nil }
+func (s *memState) RemoveFrontier(url string) error  { return nil }
+func (s *memState) LoadFrontier() ([]urlInfo, error) { return nil, nil }
+func (s *memState) Close() error                     { return nil }
+
+// crawlState persists the URL frontier and the seen/crawled sets to an
+// on-disk BoltDB file, so that `crawl` can resume where it left off after an
+// interrupted run instead of starting over.
+type crawlState struct {
+	db *bolt.DB
+}
+
+var _ crawlStateStore = (*crawlState)(nil)
+var _ crawlStateStore = (*memState)(nil)
+
+// openCrawlState opens (creating if necessary) the BoltDB file at path and
+// ensures its buckets exist.
+func openCrawlState(path string) (*crawlState, error) {
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening crawl state %q: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{seenBucket, crawledBucket, frontierBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing crawl state buckets: %w", err)
+	}
+	return &crawlState{db: db}, nil
+}
+
+func (s *crawlState) Close() error {
+	return s.db.Close()
+}
+
+// MarkSeen records that a URL has been enqueued at least once, so it won't
+// be added to the frontier a second time. It reports whether the URL was
+// newly marked.
+func (s *crawlState) MarkSeen(url string) (isNew bool, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(seenBucket)
+		if b.Get([]byte(url)) != nil {
+			isNew = false
+			return nil
+		}
+		isNew = true
+		return b.Put([]byte(url), []byte{1})
+	})
+	return isNew, err
+}
+
+// MarkCrawled records that a URL has been fetched and processed.
+func (s *crawlState) MarkCrawled(url string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(crawledBucket).Put([]byte(url), []byte{1})
+	})
+}
+
+// IsCrawled reports whether url has already been fetched and processed.
+func (s *crawlState) IsCrawled(url string) (bool, error) {
+	var crawled bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		crawled = tx.Bucket(crawledBucket).Get([]byte(url)) != nil
+		return nil
+	})
+	return crawled, err
+}
+
+// PutFrontier persists a pending urlInfo so it survives a restart before it
+// has been handed to a crawl worker.
+func (s *crawlState) PutFrontier(info urlInfo) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(frontierBucket).Put([]byte(info.val), []byte(fmt.Sprintf("%d", info.depth)))
+	})
+}
+
+// RemoveFrontier drops a urlInfo from the persisted frontier once a worker
+// has picked it up, so a resumed run doesn't re-enqueue it.
+func (s *crawlState) RemoveFrontier(url string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(frontierBucket).Delete([]byte(url))
+	})
+}
+
+// LoadFrontier returns every urlInfo left over from a previous, interrupted
+// run so `crawl` can resume from them instead of just the base URL.
+func (s *crawlState) LoadFrontier() ([]urlInfo, error) {
+	var pending []urlInfo
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(frontierBucket).ForEach(func(k, v []byte) error {
+			var depth int
+			if _, err := fmt.Sscanf(string(v), "%d", &depth); err != nil {
+				return err
+			}
+			pending = append(pending, urlInfo{val: string(k), depth: depth})
+			return nil
+		})
+	})
+	return pending, err
+}
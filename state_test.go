@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMemStateCrawledAndSeen(t *testing.T) {
+	s := newMemState()
+
+	isNew, err := s.MarkSeen("https://foo.com")
+	if err != nil || !isNew {
+		t.Fatalf("MarkSeen() = %v, %v, expected true, nil", isNew, err)
+	}
+	isNew, err = s.MarkSeen("https://foo.com")
+	if err != nil || isNew {
+		t.Fatalf("MarkSeen() on repeat = %v, %v, expected false, nil", isNew, err)
+	}
+
+	if crawled, _ := s.IsCrawled("https://foo.com"); crawled {
+		t.Errorf("IsCrawled() = true before MarkCrawled()")
+	}
+	if err := s.MarkCrawled("https://foo.com"); err != nil {
+		t.Fatalf("MarkCrawled() error = %v", err)
+	}
+	if crawled, _ := s.IsCrawled("https://foo.com"); !crawled {
+		t.Errorf("IsCrawled() = false after MarkCrawled()")
+	}
+}
+
+func TestCrawlStateFrontierPersistence(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "state.db")
+
+	s, err := openCrawlState(dbPath)
+	if err != nil {
+		t.Fatalf("openCrawlState() error = %v", err)
+	}
+
+	want := []urlInfo{{"https://a.com", 1}, {"https://b.com", 2}}
+	for _, info := range want {
+		if err := s.PutFrontier(info); err != nil {
+			t.Fatalf("PutFrontier(%v) error = %v", info, err)
+		}
+	}
+	if err := s.RemoveFrontier("https://b.com"); err != nil {
+		t.Fatalf("RemoveFrontier() error = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Reopen to make sure the frontier survives a restart.
+	s, err = openCrawlState(dbPath)
+	if err != nil {
+		t.Fatalf("re-opening crawl state error = %v", err)
+	}
+	defer s.Close()
+
+	pending, err := s.LoadFrontier()
+	if err != nil {
+		t.Fatalf("LoadFrontier() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0] != (urlInfo{"https://a.com", 1}) {
+		t.Errorf("LoadFrontier() = %v, expected only https://a.com to remain", pending)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"sync"
+	"time"
+)
+
+// warcHeader is a single "Key: Value" line of a WARC record header. WARC
+// header names (e.g. WARC-Record-ID) don't follow MIME canonicalization
+// rules, so a plain ordered slice is used instead of http.Header.
+type warcHeader struct {
+	key, value string
+}
+
+// warcWriter appends WARC/1.1 records to a gzip-compressed file, one member
+// per record as recommended by the WARC spec so individual records can be
+// decompressed without reading the whole file.
+//
+// It is safe for concurrent use by multiple crawl workers.
+type warcWriter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// newWARCWriter opens (or creates) path and writes a warcinfo record
+// describing this tool before returning the writer.
+func newWARCWriter(path string) (*warcWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating WARC file: %w", err)
+	}
+	w := &warcWriter{file: f}
+	if err := w.writeInfo(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *warcWriter) Close() error {
+	return w.file.Close()
+}
+
+func newRecordID() string {
+	var b [16]byte
+	// crypto/rand.Read on a fixed-size array never returns a short read.
+	rand.Read(b[:])
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// writeRecord gzip-compresses a single WARC record (header block + payload)
+// as its own gzip member and appends it to the file.
+func (w *warcWriter) writeRecord(recordType string, headers []warcHeader, content []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	gz := gzip.NewWriter(w.file)
+	fmt.Fprint(gz, "WARC/1.1\r\n")
+	fmt.Fprintf(gz, "WARC-Type: %s\r\n", recordType)
+	fmt.Fprintf(gz, "WARC-Date: %s\r\n", time.Now().UTC().Format(time.RFC3339))
+	for _, h := range headers {
+		fmt.Fprintf(gz, "%s: %s\r\n", h.key, h.value)
+	}
+	fmt.Fprintf(gz, "Content-Length: %d\r\n", len(content))
+	fmt.Fprint(gz, "\r\n")
+	gz.Write(content)
+	fmt.Fprint(gz, "\r\n\r\n")
+	return gz.Close()
+}
+
+func (w *warcWriter) writeInfo() error {
+	fields := "software: go-webcrawler\r\n" +
+		"format: WARC File Format 1.1\r\n"
+	headers := []warcHeader{
+		{"WARC-Record-ID", newRecordID()},
+		{"Content-Type", "application/warc-fields"},
+	}
+	return w.writeRecord("warcinfo", headers, []byte(fields))
+}
+
+// writeExchange records the HTTP request that was sent and the response it
+// received for targetURI, linking them with WARC-Concurrent-To so a replay
+// tool can reconstruct the exchange.
+func (w *warcWriter) writeExchange(targetURI string, req *http.Request, resp *http.Response, body []byte) error {
+	reqID := newRecordID()
+	respID := newRecordID()
+
+	rawReq, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return fmt.Errorf("dumping request for WARC: %w", err)
+	}
+	reqHeaders := []warcHeader{
+		{"WARC-Record-ID", reqID},
+		{"WARC-Target-URI", targetURI},
+		{"WARC-Concurrent-To", respID},
+		{"Content-Type", "application/http; msgtype=request"},
+	}
+	if err := w.writeRecord("request", reqHeaders, rawReq); err != nil {
+		return err
+	}
+
+	rawResp, err := rawResponseBytes(resp, body)
+	if err != nil {
+		return err
+	}
+	digest := sha1.Sum(body)
+	respHeaders := []warcHeader{
+		{"WARC-Record-ID", respID},
+		{"WARC-Target-URI", targetURI},
+		{"WARC-Concurrent-To", reqID},
+		{"WARC-Payload-Digest", "sha1:" + base32.StdEncoding.EncodeToString(digest[:])},
+		{"Content-Type", "application/http; msgtype=response"},
+	}
+	return w.writeRecord("response", respHeaders, rawResp)
+}
+
+// rawResponseBytes reconstructs the on-the-wire HTTP response (status line,
+// headers, body) since resp.Body has already been fully consumed by the
+// caller by the time we get here.
+func rawResponseBytes(resp *http.Response, body []byte) ([]byte, error) {
+	var buf []byte
+	statusLine := fmt.Sprintf("HTTP/%d.%d %s\r\n", resp.ProtoMajor, resp.ProtoMinor, resp.Status)
+	buf = append(buf, statusLine...)
+	if err := resp.Header.Write(sliceWriter{&buf}); err != nil {
+		return nil, fmt.Errorf("writing response headers for WARC: %w", err)
+	}
+	buf = append(buf, "\r\n"...)
+	buf = append(buf, body...)
+	return buf, nil
+}
+
+// sliceWriter adapts a *[]byte to io.Writer so http.Header.Write can append
+// directly into a buffer we already control.
+type sliceWriter struct {
+	buf *[]byte
+}
+
+func (s sliceWriter) Write(p []byte) (int, error) {
+	*s.buf = append(*s.buf, p...)
+	return len(p), nil
+}
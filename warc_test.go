@@ -0,0 +1,84 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWARCWriterRecordsExchange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.warc.gz")
+
+	w, err := newWARCWriter(path)
+	if err != nil {
+		t.Fatalf("newWARCWriter() error = %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://foo.com", nil)
+	resp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"text/html"}},
+	}
+	body := []byte("<html><body>hi</body></html>")
+	if err := w.writeExchange("https://foo.com", req, resp, body); err != nil {
+		t.Fatalf("writeExchange() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	records := readWARCRecords(t, path)
+	if len(records) != 3 {
+		t.Fatalf("got %d WARC records, expected 3 (warcinfo, request, response)", len(records))
+	}
+
+	if !strings.Contains(records[0], "WARC-Type: warcinfo") {
+		t.Errorf("first record is not warcinfo:\n%s", records[0])
+	}
+	if !strings.Contains(records[1], "WARC-Type: request") ||
+		!strings.Contains(records[1], "WARC-Target-URI: https://foo.com") {
+		t.Errorf("second record is not a tagged request record:\n%s", records[1])
+	}
+	if !strings.Contains(records[2], "WARC-Type: response") ||
+		!strings.Contains(records[2], "Content-Type: application/http; msgtype=response") ||
+		!strings.Contains(records[2], string(body)) {
+		t.Errorf("third record is not a tagged response record containing the body:\n%s", records[2])
+	}
+}
+
+// readWARCRecords ungzips path -- whose gzip.Reader transparently
+// concatenates the one-member-per-record layout written by warcWriter --
+// and splits the result back into individual WARC records.
+func readWARCRecords(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening WARC file: %v", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("reading WARC file: %v", err)
+	}
+
+	var records []string
+	for _, rec := range strings.Split(string(raw), "WARC/1.1\r\n") {
+		if rec != "" {
+			records = append(records, "WARC/1.1\r\n"+rec)
+		}
+	}
+	return records
+}